@@ -47,11 +47,33 @@ type KubeDNSConfig struct {
 	ConfigMapNs string
 	ConfigMap   string
 
-	ConfigDir    string
-	ConfigPeriod time.Duration
+	ConfigDir      string
+	ConfigPeriod   time.Duration
+	ConfigMapWatch bool
 
-	NameServers string
-	Profiling   bool
+	NameServers  string
+	ForwardZones string
+	Profiling    bool
+
+	CoreFile string
+
+	EtcdEndpoints   string
+	EtcdPathPrefix  string
+	EtcdTLSCAFile   string
+	EtcdTLSCertFile string
+	EtcdTLSKeyFile  string
+
+	MetricsPort int
+	MetricsPath string
+
+	LogFormat string
+	QueryLog  string
+
+	DNSTLSPort   int
+	DNSTLSCert   string
+	DNSTLSKey    string
+	DNSHTTPSPort int
+	DNSHTTPSPath string
 }
 
 func NewKubeDNSConfig() *KubeDNSConfig {
@@ -71,6 +93,18 @@ func NewKubeDNSConfig() *KubeDNSConfig {
 		ConfigDir:    "",
 
 		NameServers: "",
+
+		CoreFile: "",
+
+		EtcdPathPrefix: "/skydns",
+
+		MetricsPort: 10055,
+		MetricsPath: "/metrics",
+
+		LogFormat: "text",
+		QueryLog:  "off",
+
+		DNSHTTPSPath: "/dns-query",
 	}
 }
 
@@ -152,7 +186,16 @@ func (s *KubeDNSConfig) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.NameServers, "nameservers", s.NameServers,
 		"List of ip:port, separated by commas of nameservers to forward queries to. "+
 			"If set, overrides upstream servers taken from the nameserver option in /etc/resolv.conf. "+
-			"Example: 8.8.8.8:53,8.8.4.4 (default port is 53)")
+			"Example: 8.8.8.8:53,8.8.4.4 (default port is 53). Deprecated: use --forward-zones, "+
+			"which this is translated into as a wildcard \".\" zone with the default policy.")
+
+	fs.StringVar(&s.ForwardZones, "forward-zones", s.ForwardZones,
+		"semicolon separated list of per-zone conditional forwarding rules, each of the form "+
+			"\"<zone>=<ip:port>[,<ip:port>...][;policy=random|round_robin|sequential]"+
+			"[;health_check=<duration>][;expire=<duration>]\". Example: "+
+			"\"example.com=10.0.0.1:53,10.0.0.2:53;policy=round_robin;health_check=5s;expire=30s\". "+
+			"Unhealthy upstreams are skipped; if every upstream for a zone is down the query is "+
+			"answered SERVFAIL instead of blocking.")
 
 	fs.StringVar(&s.KubeConfigFile, "kubecfg-file", s.KubeConfigFile,
 		"Location of kubecfg file for access to kubernetes master service;"+
@@ -188,5 +231,60 @@ func (s *KubeDNSConfig) AddFlags(fs *pflag.FlagSet) {
 			"used in conjunction with federations or config-map flag.")
 	fs.DurationVar(&s.ConfigPeriod, "config-period", s.ConfigPeriod,
 		"period at which to check for updates in config-dir.")
+	fs.BoolVar(&s.ConfigMapWatch, "config-map-watch", s.ConfigMapWatch,
+		"whether to watch config-map via a shared informer instead of polling. "+
+			"On an ADD or UPDATE, its upstreamNameservers are applied to the running "+
+			"server synchronously as the wildcard forwarding pool, with no need to "+
+			"restart; stubDomains and federations changes are parsed but not applied. "+
+			"Deleting the config-map keeps the last applied config. "+
+			"Requires --config-map.")
 	fs.BoolVar(&s.Profiling, "profiling", s.Profiling, "specifies whether to enable profiling")
+
+	fs.StringVar(&s.CoreFile, "corefile", s.CoreFile,
+		"path to a Corefile describing the resolver as an ordered, per-zone chain of "+
+			"plugins (cache, forward, hosts, rewrite, log, errors, stubdomains, federations). "+
+			"Each zone's forward directive is looked up per query and dispatched to; the "+
+			"remaining directives are parsed into the chain but not yet executed by the "+
+			"resolver. If set, takes precedence over --nameservers, --federations, "+
+			"--config-map and --config-dir, which are otherwise synthesized into an "+
+			"equivalent chain so that behavior is preserved when this flag is absent.")
+
+	fs.StringVar(&s.EtcdEndpoints, "etcd-endpoints", s.EtcdEndpoints,
+		"comma separated list of etcd endpoints to overlay records from. If empty, "+
+			"the etcd backend is disabled.")
+	fs.StringVar(&s.EtcdPathPrefix, "etcd-path-prefix", s.EtcdPathPrefix,
+		"etcd key prefix under which records are stored, using the SkyDNS/CoreDNS "+
+			"reversed-domain layout (e.g. /skydns).")
+	fs.StringVar(&s.EtcdTLSCAFile, "etcd-tls-ca", s.EtcdTLSCAFile,
+		"path to the CA certificate used to verify the etcd server.")
+	fs.StringVar(&s.EtcdTLSCertFile, "etcd-tls-cert", s.EtcdTLSCertFile,
+		"path to the client certificate used to authenticate to etcd.")
+	fs.StringVar(&s.EtcdTLSKeyFile, "etcd-tls-key", s.EtcdTLSKeyFile,
+		"path to the client key used to authenticate to etcd.")
+
+	fs.IntVar(&s.MetricsPort, "metrics-port", s.MetricsPort,
+		"port on which to serve Prometheus metrics.")
+	fs.StringVar(&s.MetricsPath, "metrics-path", s.MetricsPath,
+		"HTTP path on which to serve Prometheus metrics.")
+
+	fs.StringVar(&s.LogFormat, "log-format", s.LogFormat,
+		"format of --query-log entries, either \"text\" or \"json\". Other log "+
+			"output is unaffected.")
+	fs.StringVar(&s.QueryLog, "query-log", s.QueryLog,
+		"per-query structured logging: \"off\" (default), \"sampled\" (~1% of queries), "+
+			"or \"full\" (every query). Each entry records client IP, qname, qtype, rcode, "+
+			"latency and the upstream used, to help debug high-cardinality resolution issues.")
+
+	fs.IntVar(&s.DNSTLSPort, "dns-tls-port", s.DNSTLSPort,
+		"port on which to serve DNS-over-TLS (RFC 7858) requests. If 0, the listener is disabled.")
+	fs.StringVar(&s.DNSTLSCert, "dns-tls-cert", s.DNSTLSCert,
+		"path to the TLS certificate used by the DNS-over-TLS and DNS-over-HTTPS listeners.")
+	fs.StringVar(&s.DNSTLSKey, "dns-tls-key", s.DNSTLSKey,
+		"path to the TLS key used by the DNS-over-TLS and DNS-over-HTTPS listeners. "+
+			"Both files are watched and hot-reloaded on SIGHUP or mtime change, so "+
+			"cert-manager-issued secrets rotate without a restart.")
+	fs.IntVar(&s.DNSHTTPSPort, "dns-https-port", s.DNSHTTPSPort,
+		"port on which to serve DNS-over-HTTPS (RFC 8484) requests. If 0, the listener is disabled.")
+	fs.StringVar(&s.DNSHTTPSPath, "dns-https-path", s.DNSHTTPSPath,
+		"HTTP path on which to serve DNS-over-HTTPS requests.")
 }
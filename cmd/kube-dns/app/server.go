@@ -0,0 +1,447 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package app wires the pieces configured in the options package together
+// into a running kube-dns server.
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/miekg/dns"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/dns/cmd/kube-dns/app/options"
+	"k8s.io/dns/pkg/dns/backend/etcd"
+	"k8s.io/dns/pkg/dns/config"
+	"k8s.io/dns/pkg/dns/forward"
+	"k8s.io/dns/pkg/dns/metrics"
+	"k8s.io/dns/pkg/dns/querylog"
+	"k8s.io/dns/pkg/dns/server"
+)
+
+// KubeDNSServer holds the subsystems built from a KubeDNSConfig and started
+// by Run.
+type KubeDNSServer struct {
+	config *options.KubeDNSConfig
+
+	corefile          *config.Corefile
+	forwardDispatcher *forward.Dispatcher
+	queryLogger       *querylog.Logger
+	etcdBackend       *etcd.Backend
+
+	configMapWatcher *config.ConfigMapWatcher
+
+	certReloader *server.CertReloader
+	tlsServer    *server.TLSServer
+	dohServer    *http.Server
+}
+
+// NewKubeDNSServerDefault builds the subsystems configured by config,
+// without starting any of them yet.
+func NewKubeDNSServerDefault(cfg *options.KubeDNSConfig) *KubeDNSServer {
+	s := &KubeDNSServer{config: cfg}
+	s.setupCorefile()
+	s.setupForwarding()
+	s.setupEtcdBackend()
+	s.setupConfigMapWatcher()
+	s.queryLogger = querylog.NewLogger(querylog.Mode(cfg.QueryLog), querylog.Format(cfg.LogFormat))
+	s.setupTLS()
+	return s
+}
+
+// setupCorefile loads --corefile if set, otherwise synthesizes an
+// equivalent Corefile from the legacy --nameservers/--federations/
+// --config-map/--config-dir flags so behavior is unchanged when
+// --corefile is absent.
+func (s *KubeDNSServer) setupCorefile() {
+	if s.config.CoreFile == "" {
+		s.corefile = config.SynthesizeCorefile(config.LegacyOptions{
+			NameServers: s.config.NameServers,
+			Federations: s.config.Federations,
+			ConfigMap:   s.config.ConfigMap,
+			ConfigDir:   s.config.ConfigDir,
+		})
+		return
+	}
+
+	f, err := os.Open(s.config.CoreFile)
+	if err != nil {
+		glog.Errorf("failed to open --corefile %s, falling back to synthesized config: %v", s.config.CoreFile, err)
+		s.corefile = config.SynthesizeCorefile(config.LegacyOptions{NameServers: s.config.NameServers})
+		return
+	}
+	defer f.Close()
+
+	parsed, err := config.ParseCorefile(f)
+	if err != nil {
+		glog.Errorf("failed to parse --corefile %s, falling back to synthesized config: %v", s.config.CoreFile, err)
+		s.corefile = config.SynthesizeCorefile(config.LegacyOptions{NameServers: s.config.NameServers})
+		return
+	}
+	s.corefile = parsed
+}
+
+// setupEtcdBackend connects to --etcd-endpoints, if configured, so records
+// published there overlay the cluster's own records.
+func (s *KubeDNSServer) setupEtcdBackend() {
+	if s.config.EtcdEndpoints == "" {
+		return
+	}
+
+	backend, err := etcd.NewBackend(etcd.Config{
+		Endpoints:   strings.Split(s.config.EtcdEndpoints, ","),
+		PathPrefix:  s.config.EtcdPathPrefix,
+		TLSCAFile:   s.config.EtcdTLSCAFile,
+		TLSCertFile: s.config.EtcdTLSCertFile,
+		TLSKeyFile:  s.config.EtcdTLSKeyFile,
+	})
+	if err != nil {
+		glog.Errorf("failed to set up etcd backend, overlay records disabled: %v", err)
+		return
+	}
+	s.etcdBackend = backend
+}
+
+// setupConfigMapWatcher builds a shared-informer watch on --config-map, which
+// Run starts, when --config-map-watch is set, instead of the legacy
+// config-dir poll loop.
+// s.forwardDispatcher is registered as the Publisher's only subscriber so
+// far, letting --config-map upstreamNameservers changes hot-swap the
+// wildcard forwarding pool without a restart; other subsystems can subscribe
+// the same way as they gain support for being hot-reloaded.
+func (s *KubeDNSServer) setupConfigMapWatcher() {
+	if !s.config.ConfigMapWatch || s.config.ConfigMap == "" {
+		return
+	}
+
+	client, err := buildKubeClient(s.config.KubeMasterURL, s.config.KubeConfigFile)
+	if err != nil {
+		glog.Errorf("failed to build a kubernetes client, config-map watch disabled: %v", err)
+		return
+	}
+
+	publisher := config.NewPublisher()
+	publisher.Subscribe(s.forwardDispatcher)
+
+	s.configMapWatcher = config.NewConfigMapWatcher(
+		client, s.config.ConfigMapNs, s.config.ConfigMap, publisher, newEventRecorder(client))
+}
+
+// buildKubeClient builds a kubernetes.Interface from --kube-master-url and
+// --kubecfg-file, falling back to in-cluster service account credentials
+// when neither is set.
+func buildKubeClient(masterURL, kubeConfigFile string) (kubernetes.Interface, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags(masterURL, kubeConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build client config: %v", err)
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// newEventRecorder returns an EventRecorder that publishes kube-dns's own
+// ConfigMap-reload events to the API server, so operators can audit config
+// changes with kubectl describe / get events.
+func newEventRecorder(client kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "kube-dns"})
+}
+
+// setupForwarding parses --forward-zones, if set, into a forward.Dispatcher.
+// Otherwise each Corefile zone's forward directive becomes a pool, so a
+// parsed --corefile (or one synthesized from --nameservers) is what
+// actually drives forwarding in the common case.
+func (s *KubeDNSServer) setupForwarding() {
+	var zones []forward.ZoneConfig
+	if s.config.ForwardZones != "" {
+		parsed, err := forward.ParseForwardZones(s.config.ForwardZones)
+		if err != nil {
+			glog.Errorf("invalid --forward-zones, forwarding disabled: %v", err)
+		} else {
+			zones = parsed
+		}
+	} else {
+		zones = zonesFromCorefile(s.corefile)
+	}
+
+	s.forwardDispatcher = forward.NewDispatcher(zones)
+}
+
+// zonesFromCorefile builds a forward.ZoneConfig, with default policy/health
+// check/expiry, for every zone in c that has a forward directive. An
+// upstream given as a file path (e.g. /etc/resolv.conf) is replaced by the
+// nameservers listed in that file, and an address without a port gets port
+// 53.
+func zonesFromCorefile(c *config.Corefile) []forward.ZoneConfig {
+	if c == nil {
+		return nil
+	}
+	var zones []forward.ZoneConfig
+	for i := range c.Zones {
+		z := &c.Zones[i]
+		var upstreams []string
+		for _, u := range z.ForwardUpstreams() {
+			if strings.HasPrefix(u, "/") {
+				if cc, err := dns.ClientConfigFromFile(u); err == nil {
+					for _, ns := range cc.Servers {
+						upstreams = append(upstreams, net.JoinHostPort(ns, cc.Port))
+					}
+				}
+			} else if _, _, err := net.SplitHostPort(u); err != nil {
+				upstreams = append(upstreams, net.JoinHostPort(u, "53"))
+			} else {
+				upstreams = append(upstreams, u)
+			}
+		}
+		if len(upstreams) == 0 {
+			continue
+		}
+		zones = append(zones, forward.ZoneConfig{
+			Zone:        z.Name,
+			Upstreams:   upstreams,
+			Policy:      forward.PolicyRandom,
+			HealthCheck: forward.DefaultHealthCheck,
+			Expire:      forward.DefaultExpire,
+		})
+	}
+	return zones
+}
+
+// setupTLS loads --dns-tls-cert/--dns-tls-key, if configured, and builds a
+// DNS-over-TLS listener on --dns-tls-port and/or a DNS-over-HTTPS listener
+// on --dns-https-port, for Run to start, sharing a single hot-reloadable
+// certificate between them.
+func (s *KubeDNSServer) setupTLS() {
+	if s.config.DNSTLSCert == "" || s.config.DNSTLSKey == "" {
+		return
+	}
+
+	reloader, err := server.NewCertReloader(s.config.DNSTLSCert, s.config.DNSTLSKey)
+	if err != nil {
+		glog.Errorf("failed to load --dns-tls-cert/--dns-tls-key, DNS-over-TLS/HTTPS disabled: %v", err)
+		return
+	}
+	s.certReloader = reloader
+
+	if s.config.DNSTLSPort != 0 {
+		addr := fmt.Sprintf("%s:%d", s.config.DNSBindAddress, s.config.DNSTLSPort)
+		s.tlsServer = server.NewTLSServer(addr, dns.HandlerFunc(s.serveDNS), reloader)
+	}
+
+	if s.config.DNSHTTPSPort != 0 {
+		addr := fmt.Sprintf("%s:%d", s.config.DNSBindAddress, s.config.DNSHTTPSPort)
+		handler := &server.DoHHandler{
+			Exchange: func(r *http.Request, req *dns.Msg) (*dns.Msg, error) {
+				return s.HandleForward(r.RemoteAddr, req, s.exchangeUpstream), nil
+			},
+		}
+		s.dohServer = server.NewDoHServer(addr, s.config.DNSHTTPSPath, handler, reloader)
+	}
+}
+
+// serveDNS is the dns.Handler entry point for the DNS-over-TLS listener.
+func (s *KubeDNSServer) serveDNS(w dns.ResponseWriter, req *dns.Msg) {
+	resp := s.HandleForward(remoteAddrString(w.RemoteAddr()), req, s.exchangeUpstream)
+	w.WriteMsg(resp)
+}
+
+// exchangeUpstream sends req to addr over UDP and returns its response; it
+// is the forward.Dispatcher-facing exchange function shared by every
+// listener.
+func (s *KubeDNSServer) exchangeUpstream(req *dns.Msg, addr string) (*dns.Msg, error) {
+	client := &dns.Client{Net: "udp", Timeout: 5 * time.Second}
+	resp, _, err := client.Exchange(req, addr)
+	return resp, err
+}
+
+// Run starts every long-running subsystem and blocks until stopCh is
+// closed.
+func (s *KubeDNSServer) Run(stopCh <-chan struct{}) {
+	s.forwardDispatcher.Run(stopCh)
+
+	if s.etcdBackend != nil {
+		go s.runEtcdBackend(stopCh)
+	}
+
+	if s.configMapWatcher != nil {
+		go s.configMapWatcher.Run(stopCh)
+	}
+
+	if s.config.MetricsPort != 0 {
+		go s.serveMetrics()
+	}
+
+	if s.certReloader != nil {
+		go s.certReloader.Watch(stopCh)
+	}
+	if s.tlsServer != nil {
+		go func() {
+			if err := s.tlsServer.ListenAndServe(); err != nil {
+				glog.Errorf("DNS-over-TLS server failed: %v", err)
+			}
+		}()
+		go func() {
+			<-stopCh
+			s.tlsServer.Shutdown()
+		}()
+	}
+	if s.dohServer != nil {
+		go func() {
+			if err := s.dohServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				glog.Errorf("DNS-over-HTTPS server failed: %v", err)
+			}
+		}()
+		go func() {
+			<-stopCh
+			s.dohServer.Shutdown(context.Background())
+		}()
+	}
+
+	<-stopCh
+}
+
+// runEtcdBackend runs the etcd backend's scan-then-watch loop until stopCh
+// is closed.
+func (s *KubeDNSServer) runEtcdBackend(stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	if err := s.etcdBackend.Run(ctx); err != nil {
+		glog.Errorf("etcd backend failed: %v", err)
+	}
+}
+
+// serveMetrics serves the Prometheus metrics endpoint until it fails; it is
+// run in its own goroutine for the lifetime of the server.
+func (s *KubeDNSServer) serveMetrics() {
+	mux := http.NewServeMux()
+	mux.Handle(s.config.MetricsPath, metrics.Handler())
+	addr := fmt.Sprintf(":%d", s.config.MetricsPort)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		glog.Errorf("metrics server on %s failed: %v", addr, err)
+	}
+}
+
+// HandleForward resolves req, recording its latency and outcome to
+// Prometheus and to the query logger. It is the dns.Handler-facing entry
+// point for queries that fall through to a configured forward zone.
+// clientAddr is the originating client's address as a string (e.g. from
+// dns.ResponseWriter.RemoteAddr or an http.Request.RemoteAddr), or "" if
+// unknown.
+func (s *KubeDNSServer) HandleForward(clientAddr string, req *dns.Msg, exchange func(*dns.Msg, string) (*dns.Msg, error)) *dns.Msg {
+	var upstream string
+	recordingExchange := func(m *dns.Msg, addr string) (*dns.Msg, error) {
+		r, err := exchange(m, addr)
+		if err == nil {
+			upstream = addr
+		}
+		return r, err
+	}
+
+	start := time.Now()
+	resp, zone := s.resolve(req, recordingExchange)
+	latency := time.Since(start)
+
+	qtype, qname := "", ""
+	if len(req.Question) > 0 {
+		qtype = dns.TypeToString[req.Question[0].Qtype]
+		qname = req.Question[0].Name
+	}
+	rcode := dns.RcodeToString[resp.Rcode]
+
+	// zone, not qname, is the metrics label: qname is an unbounded,
+	// per-Service/Pod value, while zone is the bounded set of configured
+	// Corefile zones.
+	metrics.RecordQuery(qtype, rcode, zone, latency)
+	s.queryLogger.Log(querylog.Entry{
+		ClientIP: clientAddr,
+		QName:    qname,
+		QType:    qtype,
+		RCode:    rcode,
+		Latency:  latency,
+		Upstream: upstream,
+	})
+	return resp
+}
+
+// resolve answers req from the etcd overlay if it holds a matching record,
+// otherwise looks up req's Corefile zone via ZoneFor and forwards to that
+// zone's pool; if no Corefile zone has a forward directive (e.g. it only
+// set --forward-zones), it falls back to the dispatcher's own suffix match.
+// It also returns the matched Corefile zone name (or "." if none matched),
+// for the caller to use as a bounded-cardinality metrics label.
+func (s *KubeDNSServer) resolve(req *dns.Msg, exchange func(*dns.Msg, string) (*dns.Msg, error)) (*dns.Msg, string) {
+	if len(req.Question) == 0 {
+		fail := new(dns.Msg)
+		fail.SetRcode(req, dns.RcodeServerFailure)
+		return fail, "."
+	}
+	q := req.Question[0]
+
+	if resp := s.answerFromEtcd(req, q); resp != nil {
+		return resp, "."
+	}
+
+	if z := s.corefile.ZoneFor(q.Name); z != nil {
+		if pool := s.forwardDispatcher.PoolForZone(z.Name); pool != nil {
+			return s.forwardDispatcher.ForwardToPool(pool, req, exchange), z.Name
+		}
+		return s.forwardDispatcher.Forward(req, exchange), z.Name
+	}
+	return s.forwardDispatcher.Forward(req, exchange), "."
+}
+
+// answerFromEtcd returns a response built from the etcd overlay's records
+// for q, or nil if the backend is disabled or holds no matching record, so
+// the caller can fall through to forwarding.
+func (s *KubeDNSServer) answerFromEtcd(req *dns.Msg, q dns.Question) *dns.Msg {
+	if s.etcdBackend == nil {
+		return nil
+	}
+	rrs := s.etcdBackend.RRs(q.Name, q.Qtype)
+	if len(rrs) == 0 {
+		return nil
+	}
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Answer = rrs
+	return resp
+}
+
+// remoteAddrString returns addr.String(), or "" if addr is nil, so it can be
+// passed directly as HandleForward's clientAddr.
+func remoteAddrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
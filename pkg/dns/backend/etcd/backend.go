@@ -0,0 +1,217 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/golang/glog"
+)
+
+// Config configures a Backend.
+type Config struct {
+	Endpoints  []string
+	PathPrefix string
+
+	TLSCAFile   string
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// Backend overlays records read from an etcd keyspace, built with the
+// SkyDNS/CoreDNS key layout, on top of kube-dns's cluster records. It keeps
+// an in-memory cache populated by an initial scan and kept current by a
+// watch on PathPrefix.
+type Backend struct {
+	client     *clientv3.Client
+	pathPrefix string
+
+	mu    sync.RWMutex
+	cache map[string][]Record
+}
+
+// NewBackend connects to the configured etcd endpoints and returns a Backend
+// ready to have Run called on it.
+func NewBackend(cfg Config) (*Backend, error) {
+	clientCfg := clientv3.Config{
+		Endpoints:   cfg.Endpoints,
+		DialTimeout: 5 * time.Second,
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" || cfg.TLSCAFile != "" {
+		tlsConfig, err := buildTLSConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build etcd TLS config: %v", err)
+		}
+		clientCfg.TLS = tlsConfig
+	}
+
+	client, err := clientv3.New(clientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create etcd client: %v", err)
+	}
+
+	return &Backend{
+		client:     client,
+		pathPrefix: cfg.PathPrefix,
+		cache:      make(map[string][]Record),
+	}, nil
+}
+
+func buildTLSConfig(cfg Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if cfg.TLSCAFile != "" {
+		ca, err := ioutil.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Run performs the initial scan of pathPrefix and then watches it for
+// changes until ctx is done, keeping the in-memory cache current.
+func (b *Backend) Run(ctx context.Context) error {
+	if err := b.scan(ctx); err != nil {
+		return err
+	}
+
+	watchCh := b.client.Watch(ctx, b.pathPrefix, clientv3.WithPrefix())
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case resp, ok := <-watchCh:
+			if !ok {
+				return nil
+			}
+			if err := resp.Err(); err != nil {
+				glog.Errorf("etcd watch on %s failed: %v", b.pathPrefix, err)
+				continue
+			}
+			b.applyEvents(resp.Events)
+		}
+	}
+}
+
+func (b *Backend) scan(ctx context.Context) error {
+	resp, err := b.client.Get(ctx, b.pathPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("failed to scan etcd prefix %s: %v", b.pathPrefix, err)
+	}
+
+	cache := make(map[string][]Record)
+	for _, kv := range resp.Kvs {
+		domain, rec, err := decode(b.pathPrefix, kv.Key, kv.Value)
+		if err != nil {
+			glog.Warningf("skipping invalid etcd record at %s: %v", kv.Key, err)
+			continue
+		}
+		cache[domain] = append(cache[domain], rec)
+	}
+
+	b.mu.Lock()
+	b.cache = cache
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *Backend) applyEvents(events []*clientv3.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ev := range events {
+		domain := domainForPath(b.pathPrefix, string(ev.Kv.Key))
+		switch ev.Type {
+		case clientv3.EventTypeDelete:
+			delete(b.cache, domain)
+		case clientv3.EventTypePut:
+			_, rec, err := decode(b.pathPrefix, ev.Kv.Key, ev.Kv.Value)
+			if err != nil {
+				glog.Warningf("skipping invalid etcd record at %s: %v", ev.Kv.Key, err)
+				continue
+			}
+			b.cache[domain] = []Record{rec}
+		}
+	}
+}
+
+func decode(prefix string, key, value []byte) (string, Record, error) {
+	var rec Record
+	if err := json.Unmarshal(value, &rec); err != nil {
+		return "", Record{}, err
+	}
+	if rec.TTL == 0 {
+		rec.TTL = uint32(DefaultTTL.Seconds())
+	}
+	return domainForPath(prefix, string(key)), rec, nil
+}
+
+// Records returns the records overlaid for the given fully qualified
+// domain name, or nil if none are present. In addition to an exact match,
+// it also matches keys stored one level below name in etcd: the
+// SkyDNS/CoreDNS convention for storing multiple records under one owner
+// name is an extra, non-label "uniquifier" path segment below the name
+// (e.g. /skydns/com/example/www/<uid1> and .../www/<uid2> both answer for
+// "www.example.com."), which reconstructs as a single extra leftmost label
+// that must be stripped back off.
+func (b *Backend) Records(name string) []Record {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var out []Record
+	out = append(out, b.cache[name]...)
+	for domain, recs := range b.cache {
+		if domain != name && parentDomain(domain) == name {
+			out = append(out, recs...)
+		}
+	}
+	return out
+}
+
+// parentDomain strips the leftmost label off domain, or returns "" if
+// domain has no parent (the root or a single label).
+func parentDomain(domain string) string {
+	i := strings.Index(domain, ".")
+	if i < 0 || i == len(domain)-1 {
+		return ""
+	}
+	return domain[i+1:]
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import "testing"
+
+func TestParentDomain(t *testing.T) {
+	cases := []struct {
+		domain string
+		want   string
+	}{
+		{"uid1.www.example.com.", "www.example.com."},
+		{"www.example.com.", "example.com."},
+		{"com.", ""},
+		{"com", ""},
+	}
+	for _, c := range cases {
+		if got := parentDomain(c.domain); got != c.want {
+			t.Errorf("parentDomain(%q) = %q, want %q", c.domain, got, c.want)
+		}
+	}
+}
+
+func TestRecordsMergesUniquifierKeys(t *testing.T) {
+	b := &Backend{
+		cache: map[string][]Record{
+			"uid1.www.example.com.": {{Host: "10.0.0.1"}},
+			"uid2.www.example.com.": {{Host: "10.0.0.2"}},
+			"other.example.com.":    {{Host: "10.0.0.9"}},
+		},
+	}
+
+	recs := b.Records("www.example.com.")
+	if len(recs) != 2 {
+		t.Fatalf("Records(www.example.com.) returned %d records, want 2: %+v", len(recs), recs)
+	}
+
+	hosts := map[string]bool{}
+	for _, r := range recs {
+		hosts[r.Host] = true
+	}
+	if !hosts["10.0.0.1"] || !hosts["10.0.0.2"] {
+		t.Errorf("Records(www.example.com.) = %+v, want records for both 10.0.0.1 and 10.0.0.2", recs)
+	}
+}
+
+func TestRecordsExactMatch(t *testing.T) {
+	b := &Backend{
+		cache: map[string][]Record{
+			"www.example.com.": {{Host: "10.0.0.1"}},
+		},
+	}
+	recs := b.Records("www.example.com.")
+	if len(recs) != 1 || recs[0].Host != "10.0.0.1" {
+		t.Errorf("Records(www.example.com.) = %+v, want a single record for 10.0.0.1", recs)
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import "strings"
+
+// pathForDomain converts a DNS name into the reversed-label etcd path used
+// by SkyDNS/CoreDNS, e.g. "foo.example.com." under prefix "/skydns" becomes
+// "/skydns/com/example/foo".
+func pathForDomain(prefix, domain string) string {
+	domain = strings.TrimSuffix(domain, ".")
+	labels := strings.Split(domain, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.TrimRight(prefix, "/") + "/" + strings.Join(labels, "/")
+}
+
+// domainForPath is the inverse of pathForDomain: it recovers the DNS name a
+// key corresponds to, for use when handling watch events.
+func domainForPath(prefix, path string) string {
+	path = strings.TrimPrefix(path, strings.TrimRight(prefix, "/")+"/")
+	labels := strings.Split(path, "/")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, ".") + "."
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import "testing"
+
+func TestPathForDomain(t *testing.T) {
+	cases := []struct {
+		domain string
+		want   string
+	}{
+		{"foo.example.com.", "/skydns/com/example/foo"},
+		{"foo.example.com", "/skydns/com/example/foo"},
+		{"example.com.", "/skydns/com/example"},
+	}
+	for _, c := range cases {
+		if got := pathForDomain("/skydns", c.domain); got != c.want {
+			t.Errorf("pathForDomain(%q) = %q, want %q", c.domain, got, c.want)
+		}
+	}
+}
+
+func TestDomainForPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/skydns/com/example/foo", "foo.example.com."},
+		{"/skydns/com/example", "example.com."},
+		{"/skydns/com/example/www/uid1", "uid1.www.example.com."},
+	}
+	for _, c := range cases {
+		if got := domainForPath("/skydns", c.path); got != c.want {
+			t.Errorf("domainForPath(%q) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestPathDomainRoundTrip(t *testing.T) {
+	domain := "foo.example.com."
+	path := pathForDomain("/skydns", domain)
+	if got := domainForPath("/skydns", path); got != domain {
+		t.Errorf("round trip: pathForDomain -> domainForPath = %q, want %q", got, domain)
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package etcd overlays DNS records read from an etcd keyspace laid out the
+// way SkyDNS and CoreDNS's etcd plugin do, on top of the records kube-dns
+// already serves for the cluster. It lets external controllers (federation
+// providers, external-dns and similar tools) publish records that kube-dns
+// will answer for without going through the Kubernetes API.
+package etcd
+
+import "time"
+
+// Record is the JSON value stored at an etcd key, using the SkyDNS schema.
+type Record struct {
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	Weight   int    `json:"weight,omitempty"`
+	Text     string `json:"text,omitempty"`
+	TTL      uint32 `json:"ttl,omitempty"`
+}
+
+// DefaultTTL is used when a Record does not specify one, matching SkyDNS's
+// default.
+const DefaultTTL = 30 * time.Second
@@ -0,0 +1,74 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// RRs converts the records overlaid for name into resource records of the
+// requested qtype. Unsupported qtypes yield no records so the caller can
+// fall through to the next plugin in the chain.
+func (b *Backend) RRs(name string, qtype uint16) []dns.RR {
+	var rrs []dns.RR
+	for _, rec := range b.Records(name) {
+		rr := toRR(name, qtype, rec)
+		if rr != nil {
+			rrs = append(rrs, rr)
+		}
+	}
+	return rrs
+}
+
+func toRR(name string, qtype uint16, rec Record) dns.RR {
+	hdr := dns.RR_Header{Name: name, Class: dns.ClassINET, Ttl: rec.TTL}
+
+	switch qtype {
+	case dns.TypeA:
+		ip := net.ParseIP(rec.Host)
+		if ip == nil || ip.To4() == nil {
+			return nil
+		}
+		hdr.Rrtype = dns.TypeA
+		return &dns.A{Hdr: hdr, A: ip.To4()}
+	case dns.TypeAAAA:
+		ip := net.ParseIP(rec.Host)
+		if ip == nil || ip.To4() != nil {
+			return nil
+		}
+		hdr.Rrtype = dns.TypeAAAA
+		return &dns.AAAA{Hdr: hdr, AAAA: ip}
+	case dns.TypeSRV:
+		hdr.Rrtype = dns.TypeSRV
+		return &dns.SRV{
+			Hdr:      hdr,
+			Priority: uint16(rec.Priority),
+			Weight:   uint16(rec.Weight),
+			Port:     uint16(rec.Port),
+			Target:   dns.Fqdn(rec.Host),
+		}
+	case dns.TypeTXT:
+		if rec.Text == "" {
+			return nil
+		}
+		hdr.Rrtype = dns.TypeTXT
+		return &dns.TXT{Hdr: hdr, Txt: []string{rec.Text}}
+	}
+	return nil
+}
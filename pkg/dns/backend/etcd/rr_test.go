@@ -0,0 +1,59 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestToRR(t *testing.T) {
+	name := "www.example.com."
+
+	if rr := toRR(name, dns.TypeA, Record{Host: "10.0.0.1", TTL: 30}); rr == nil {
+		t.Error("toRR A record = nil, want an A record")
+	} else if a, ok := rr.(*dns.A); !ok || a.A.String() != "10.0.0.1" {
+		t.Errorf("toRR A record = %+v, want A 10.0.0.1", rr)
+	}
+
+	if rr := toRR(name, dns.TypeA, Record{Host: "::1", TTL: 30}); rr != nil {
+		t.Errorf("toRR A record for an IPv6 host = %+v, want nil", rr)
+	}
+
+	if rr := toRR(name, dns.TypeAAAA, Record{Host: "::1", TTL: 30}); rr == nil {
+		t.Error("toRR AAAA record = nil, want an AAAA record")
+	}
+
+	if rr := toRR(name, dns.TypeSRV, Record{Host: "target.example.com", Port: 80, Priority: 10, Weight: 5}); rr == nil {
+		t.Error("toRR SRV record = nil, want an SRV record")
+	} else if srv, ok := rr.(*dns.SRV); !ok || srv.Port != 80 {
+		t.Errorf("toRR SRV record = %+v, want Port 80", rr)
+	}
+
+	if rr := toRR(name, dns.TypeTXT, Record{Text: "hello"}); rr == nil {
+		t.Error("toRR TXT record = nil, want a TXT record")
+	}
+
+	if rr := toRR(name, dns.TypeTXT, Record{}); rr != nil {
+		t.Errorf("toRR TXT record with no text = %+v, want nil", rr)
+	}
+
+	if rr := toRR(name, dns.TypeMX, Record{Host: "10.0.0.1"}); rr != nil {
+		t.Errorf("toRR for an unsupported qtype = %+v, want nil", rr)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+// ConfigMap data keys, matching the kube-dns ConfigMap schema.
+const (
+	stubDomainsKey         = "stubDomains"
+	upstreamNameserversKey = "upstreamNameservers"
+	federationsKey         = "federations"
+)
+
+// configFromConfigMap parses the stubDomains, upstreamNameservers and
+// federations keys out of a kube-dns ConfigMap's Data.
+func configFromConfigMap(cm *v1.ConfigMap) (*Config, error) {
+	cfg := &Config{}
+
+	if raw, ok := cm.Data[stubDomainsKey]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.StubDomains); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", stubDomainsKey, err)
+		}
+	}
+
+	if raw, ok := cm.Data[upstreamNameserversKey]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.UpstreamNameservers); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", upstreamNameserversKey, err)
+		}
+	}
+
+	if raw, ok := cm.Data[federationsKey]; ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Federations); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", federationsKey, err)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func TestConfigFromConfigMap(t *testing.T) {
+	cm := &v1.ConfigMap{Data: map[string]string{
+		stubDomainsKey:         `{"acme.local": ["1.2.3.4"]}`,
+		upstreamNameserversKey: `["8.8.8.8:53", "8.8.4.4:53"]`,
+		federationsKey:         `{"myfederation": "example.com"}`,
+	}}
+
+	got, err := configFromConfigMap(cm)
+	if err != nil {
+		t.Fatalf("configFromConfigMap returned error: %v", err)
+	}
+
+	want := &Config{
+		StubDomains:         map[string][]string{"acme.local": {"1.2.3.4"}},
+		UpstreamNameservers: []string{"8.8.8.8:53", "8.8.4.4:53"},
+		Federations:         map[string]string{"myfederation": "example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("configFromConfigMap = %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigFromConfigMapEmpty(t *testing.T) {
+	got, err := configFromConfigMap(&v1.ConfigMap{})
+	if err != nil {
+		t.Fatalf("configFromConfigMap returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, &Config{}) {
+		t.Errorf("configFromConfigMap(empty) = %+v, want &Config{}", got)
+	}
+}
+
+func TestConfigFromConfigMapInvalidJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"stubDomains", stubDomainsKey},
+		{"upstreamNameservers", upstreamNameserversKey},
+		{"federations", federationsKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &v1.ConfigMap{Data: map[string]string{tt.key: "not valid json"}}
+			if _, err := configFromConfigMap(cm); err == nil {
+				t.Errorf("configFromConfigMap with invalid %s returned no error, want one", tt.key)
+			}
+		})
+	}
+}
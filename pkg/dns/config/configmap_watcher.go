@@ -0,0 +1,131 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/dns/pkg/dns/metrics"
+)
+
+// ConfigMapWatcher keeps a Publisher in sync with a single ConfigMap via a
+// shared informer, instead of the legacy config-dir poll loop. It pushes an
+// update to every subscriber on ADD and UPDATE and records a Kubernetes
+// Event for each applied change so operators can audit when and why
+// resolution behavior changed. On DELETE it only logs a warning: nothing is
+// pushed, and subscribers keep the last applied config.
+type ConfigMapWatcher struct {
+	namespace string
+	name      string
+
+	client    kubernetes.Interface
+	publisher *Publisher
+	recorder  record.EventRecorder
+
+	store      cache.Store
+	controller cache.Controller
+}
+
+// NewConfigMapWatcher returns a watcher for the ConfigMap named name in
+// namespace, publishing parsed updates to publisher and recording events via
+// recorder.
+func NewConfigMapWatcher(client kubernetes.Interface, namespace, name string, publisher *Publisher, recorder record.EventRecorder) *ConfigMapWatcher {
+	w := &ConfigMapWatcher{
+		namespace: namespace,
+		name:      name,
+		client:    client,
+		publisher: publisher,
+		recorder:  recorder,
+	}
+
+	selector := fields.OneTermEqualSelector("metadata.name", name)
+	store, controller := cache.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = selector.String()
+				return client.CoreV1().ConfigMaps(namespace).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = selector.String()
+				return client.CoreV1().ConfigMaps(namespace).Watch(options)
+			},
+		},
+		&v1.ConfigMap{},
+		0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) { w.onChange(obj.(*v1.ConfigMap), "added") },
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				w.onChange(newObj.(*v1.ConfigMap), "updated")
+			},
+			DeleteFunc: func(obj interface{}) { w.onDelete() },
+		},
+	)
+	w.store = store
+	w.controller = controller
+	return w
+}
+
+// Run starts the underlying informer and blocks until stopCh is closed.
+func (w *ConfigMapWatcher) Run(stopCh <-chan struct{}) {
+	w.controller.Run(stopCh)
+}
+
+// HasSynced reports whether the initial list has completed.
+func (w *ConfigMapWatcher) HasSynced() bool {
+	return w.controller.HasSynced()
+}
+
+func (w *ConfigMapWatcher) onChange(cm *v1.ConfigMap, reason string) {
+	cfg, err := configFromConfigMap(cm)
+	if err != nil {
+		glog.Errorf("config-map %s/%s %s but could not be parsed: %v", w.namespace, w.name, reason, err)
+		metrics.RecordConfigReload("failure")
+		w.event(cm, v1.EventTypeWarning, "ConfigReloadFailed", fmt.Sprintf("failed to parse config-map: %v", err))
+		return
+	}
+	if err := w.publisher.Publish(cfg); err != nil {
+		glog.Errorf("config-map %s/%s %s but could not be applied: %v", w.namespace, w.name, reason, err)
+		metrics.RecordConfigReload("failure")
+		w.event(cm, v1.EventTypeWarning, "ConfigReloadFailed", fmt.Sprintf("failed to apply config: %v", err))
+		return
+	}
+	glog.V(0).Infof("config-map %s/%s %s, new config applied", w.namespace, w.name, reason)
+	metrics.RecordConfigReload("success")
+	w.event(cm, v1.EventTypeNormal, "ConfigReloaded", fmt.Sprintf("config %s and applied", reason))
+}
+
+func (w *ConfigMapWatcher) onDelete() {
+	glog.Warningf("config-map %s/%s deleted, keeping last known good config", w.namespace, w.name)
+}
+
+func (w *ConfigMapWatcher) event(cm *v1.ConfigMap, eventType, reason, message string) {
+	if w.recorder == nil || cm == nil {
+		return
+	}
+	w.recorder.Event(cm, eventType, reason, message)
+}
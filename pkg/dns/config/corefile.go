@@ -0,0 +1,141 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config parses the kube-dns resolver configuration, including the
+// Corefile plugin-chain format and the legacy flag/ConfigMap based config.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Plugin is a single, ordered handler in a zone's plugin chain. Name
+// identifies the plugin (cache, forward, hosts, rewrite, log, errors,
+// stubdomains, federations) and Args holds its unparsed directive arguments,
+// to be interpreted by the plugin itself.
+type Plugin struct {
+	Name string
+	Args []string
+}
+
+// Zone is the set of plugins configured for a single zone block in a
+// Corefile, applied in the order they were declared.
+type Zone struct {
+	Name    string
+	Plugins []Plugin
+}
+
+// Corefile is a parsed Corefile: an ordered chain of plugins per zone. A
+// query is dispatched to the chain of the longest matching zone suffix.
+type Corefile struct {
+	Zones []Zone
+}
+
+// ForwardUpstreams returns the upstream addresses of the zone's forward
+// directive (e.g. "forward . 10.0.0.1:53 10.0.0.2:53" yields
+// ["10.0.0.1:53", "10.0.0.2:53"]), or nil if the zone has no forward
+// directive.
+func (z *Zone) ForwardUpstreams() []string {
+	for _, p := range z.Plugins {
+		if p.Name == "forward" && len(p.Args) > 1 {
+			return p.Args[1:]
+		}
+	}
+	return nil
+}
+
+// ZoneFor returns the Zone whose Name is the longest suffix match of qname,
+// or nil if no zone matches.
+func (c *Corefile) ZoneFor(qname string) *Zone {
+	qname = fqdn(strings.ToLower(qname))
+	var best *Zone
+	for i := range c.Zones {
+		z := &c.Zones[i]
+		if z.Name == "." || strings.HasSuffix(qname, fqdn(strings.ToLower(z.Name))) {
+			if best == nil || len(z.Name) > len(best.Name) {
+				best = z
+			}
+		}
+	}
+	return best
+}
+
+// fqdn appends a trailing dot to s if it does not already have one, so
+// zone names and query names compare as fully qualified domain names
+// regardless of how each was written.
+func fqdn(s string) string {
+	if strings.HasSuffix(s, ".") {
+		return s
+	}
+	return s + "."
+}
+
+// ParseCorefile parses a Corefile in the CoreDNS style:
+//
+//	example.com {
+//	    cache 30
+//	    forward . 10.0.0.1:53 10.0.0.2:53
+//	}
+//	. {
+//	    errors
+//	    forward . /etc/resolv.conf
+//	}
+func ParseCorefile(r io.Reader) (*Corefile, error) {
+	scanner := bufio.NewScanner(r)
+	c := &Corefile{}
+	var cur *Zone
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+		if text == "" {
+			continue
+		}
+		switch {
+		case cur == nil && strings.HasSuffix(text, "{"):
+			name := strings.TrimSpace(strings.TrimSuffix(text, "{"))
+			if name == "" {
+				return nil, fmt.Errorf("corefile:%d: zone block missing a name", line)
+			}
+			c.Zones = append(c.Zones, Zone{Name: name})
+			cur = &c.Zones[len(c.Zones)-1]
+		case cur != nil && text == "}":
+			cur = nil
+		case cur != nil:
+			fields := strings.Fields(text)
+			cur.Plugins = append(cur.Plugins, Plugin{Name: fields[0], Args: fields[1:]})
+		default:
+			return nil, fmt.Errorf("corefile:%d: directive %q outside of a zone block", line, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if cur != nil {
+		return nil, fmt.Errorf("corefile: unterminated zone block %q", cur.Name)
+	}
+	return c, nil
+}
+
+func stripComment(s string) string {
+	if i := strings.Index(s, "#"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCorefile(t *testing.T) {
+	in := `
+example.com {
+    cache 30
+    forward . 10.0.0.1:53 10.0.0.2:53
+}
+# a comment on its own line
+. {
+    errors
+    forward . /etc/resolv.conf
+}
+`
+	got, err := ParseCorefile(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ParseCorefile returned error: %v", err)
+	}
+
+	want := &Corefile{Zones: []Zone{
+		{
+			Name: "example.com",
+			Plugins: []Plugin{
+				{Name: "cache", Args: []string{"30"}},
+				{Name: "forward", Args: []string{".", "10.0.0.1:53", "10.0.0.2:53"}},
+			},
+		},
+		{
+			Name: ".",
+			Plugins: []Plugin{
+				{Name: "errors"},
+				{Name: "forward", Args: []string{".", "/etc/resolv.conf"}},
+			},
+		},
+	}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCorefile = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseCorefileErrors(t *testing.T) {
+	cases := []string{
+		"cache 30\n",             // directive outside a zone block
+		"{\n  cache\n}\n",        // zone block with no name
+		"example.com {\n cache\n", // unterminated block
+	}
+	for _, in := range cases {
+		if _, err := ParseCorefile(strings.NewReader(in)); err == nil {
+			t.Errorf("ParseCorefile(%q) = nil error, want an error", in)
+		}
+	}
+}
+
+func TestZoneFor(t *testing.T) {
+	c := &Corefile{Zones: []Zone{
+		{Name: "."},
+		{Name: "example.com"},
+		{Name: "svc.example.com"},
+	}}
+
+	cases := []struct {
+		qname string
+		want  string
+	}{
+		{"www.svc.example.com.", "svc.example.com"},
+		{"www.example.com.", "example.com"},
+		{"other.org.", "."},
+	}
+	for _, c2 := range cases {
+		got := c.ZoneFor(c2.qname)
+		if got == nil || got.Name != c2.want {
+			t.Errorf("ZoneFor(%q) = %v, want zone %q", c2.qname, got, c2.want)
+		}
+	}
+}
+
+func TestZoneForwardUpstreams(t *testing.T) {
+	cases := []struct {
+		name string
+		zone Zone
+		want []string
+	}{
+		{
+			name: "forward directive present",
+			zone: Zone{Plugins: []Plugin{{Name: "forward", Args: []string{".", "10.0.0.1:53", "10.0.0.2:53"}}}},
+			want: []string{"10.0.0.1:53", "10.0.0.2:53"},
+		},
+		{
+			name: "no forward directive",
+			zone: Zone{Plugins: []Plugin{{Name: "cache", Args: []string{"30"}}}},
+			want: nil,
+		},
+		{
+			name: "forward directive with no upstreams",
+			zone: Zone{Plugins: []Plugin{{Name: "forward", Args: []string{"."}}}},
+			want: nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.zone.ForwardUpstreams(); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("ForwardUpstreams() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// Config is the mutable set of values that can be hot-swapped into a
+// running server, as parsed from a watched ConfigMap.
+type Config struct {
+	StubDomains         map[string][]string
+	UpstreamNameservers []string
+	Federations         map[string]string
+}
+
+// Subscriber is implemented by anything that needs to react to a
+// configuration change applied while the server is running, such as the
+// stubDomains cache, the upstream nameserver pool, or the federations map.
+// ApplyConfig must be safe to call concurrently with query handling.
+type Subscriber interface {
+	ApplyConfig(*Config) error
+}
+
+// Publisher fans a new Config out to every registered Subscriber, collecting
+// and returning the first error encountered so callers can decide whether to
+// keep the previous config or surface the failure (e.g. as a Kubernetes
+// Event).
+type Publisher struct {
+	subscribers []Subscriber
+}
+
+// NewPublisher returns a Publisher with no subscribers registered.
+func NewPublisher() *Publisher {
+	return &Publisher{}
+}
+
+// Subscribe registers s to receive future config updates.
+func (p *Publisher) Subscribe(s Subscriber) {
+	p.subscribers = append(p.subscribers, s)
+}
+
+// Publish applies cfg to every subscriber in registration order, returning
+// the first error encountered. Subscribers already applied before the
+// failing one keep the new config; it is up to the caller to decide whether
+// a partial apply is acceptable.
+func (p *Publisher) Publish(cfg *Config) error {
+	for _, s := range p.subscribers {
+		if err := s.ApplyConfig(cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
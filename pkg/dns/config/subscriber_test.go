@@ -0,0 +1,71 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"testing"
+)
+
+type fakeSubscriber struct {
+	applied []*Config
+	err     error
+}
+
+func (f *fakeSubscriber) ApplyConfig(cfg *Config) error {
+	f.applied = append(f.applied, cfg)
+	return f.err
+}
+
+func TestPublisherPublishFansOutInOrder(t *testing.T) {
+	a, b := &fakeSubscriber{}, &fakeSubscriber{}
+	p := NewPublisher()
+	p.Subscribe(a)
+	p.Subscribe(b)
+
+	cfg := &Config{UpstreamNameservers: []string{"10.0.0.1:53"}}
+	if err := p.Publish(cfg); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+
+	for name, sub := range map[string]*fakeSubscriber{"a": a, "b": b} {
+		if len(sub.applied) != 1 || sub.applied[0] != cfg {
+			t.Errorf("subscriber %s.applied = %v, want [cfg]", name, sub.applied)
+		}
+	}
+}
+
+func TestPublisherPublishStopsOnFirstError(t *testing.T) {
+	failing := &fakeSubscriber{err: fmt.Errorf("boom")}
+	after := &fakeSubscriber{}
+	p := NewPublisher()
+	p.Subscribe(failing)
+	p.Subscribe(after)
+
+	if err := p.Publish(&Config{}); err == nil {
+		t.Fatal("Publish returned no error, want the first subscriber's error")
+	}
+	if len(after.applied) != 0 {
+		t.Errorf("subscriber after the failing one was applied %d times, want 0", len(after.applied))
+	}
+}
+
+func TestPublisherPublishNoSubscribers(t *testing.T) {
+	if err := NewPublisher().Publish(&Config{}); err != nil {
+		t.Errorf("Publish with no subscribers returned error: %v", err)
+	}
+}
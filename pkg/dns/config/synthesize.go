@@ -0,0 +1,63 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "strings"
+
+// LegacyOptions carries the subset of the pre-Corefile flags/ConfigMap
+// settings that SynthesizeCorefile translates into an equivalent plugin
+// chain, so resolver behavior is unchanged when --corefile is not set.
+type LegacyOptions struct {
+	NameServers string
+	Federations map[string]string
+	ConfigMap   string
+	ConfigDir   string
+}
+
+// SynthesizeCorefile builds a Corefile equivalent to the legacy flag-driven
+// configuration: cluster records and stubDomains served from the "." zone
+// via the hosts/stubdomains plugins, --nameservers translated into a
+// forward directive, and --federations into a federations directive.
+func SynthesizeCorefile(opts LegacyOptions) *Corefile {
+	root := Zone{Name: "."}
+	root.Plugins = append(root.Plugins, Plugin{Name: "errors"})
+	root.Plugins = append(root.Plugins, Plugin{Name: "log"})
+	root.Plugins = append(root.Plugins, Plugin{Name: "cache"})
+	root.Plugins = append(root.Plugins, Plugin{Name: "hosts"})
+
+	if opts.ConfigMap != "" || opts.ConfigDir != "" {
+		root.Plugins = append(root.Plugins, Plugin{Name: "stubdomains"})
+	}
+
+	if len(opts.Federations) > 0 {
+		args := make([]string, 0, len(opts.Federations)*2)
+		for name, domain := range opts.Federations {
+			args = append(args, name, domain)
+		}
+		root.Plugins = append(root.Plugins, Plugin{Name: "federations", Args: args})
+	}
+
+	if opts.NameServers != "" {
+		servers := strings.Split(opts.NameServers, ",")
+		args := append([]string{"."}, servers...)
+		root.Plugins = append(root.Plugins, Plugin{Name: "forward", Args: args})
+	} else {
+		root.Plugins = append(root.Plugins, Plugin{Name: "forward", Args: []string{".", "/etc/resolv.conf"}})
+	}
+
+	return &Corefile{Zones: []Zone{root}}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "testing"
+
+func pluginNames(z Zone) []string {
+	var names []string
+	for _, p := range z.Plugins {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func hasPlugin(z Zone, name string) bool {
+	for _, n := range pluginNames(z) {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSynthesizeCorefileDefaults(t *testing.T) {
+	c := SynthesizeCorefile(LegacyOptions{})
+	if len(c.Zones) != 1 || c.Zones[0].Name != "." {
+		t.Fatalf("SynthesizeCorefile() = %+v, want a single \".\" zone", c.Zones)
+	}
+	z := c.Zones[0]
+	if !hasPlugin(z, "forward") {
+		t.Errorf("zone %+v missing a forward plugin", z)
+	}
+	if hasPlugin(z, "stubdomains") {
+		t.Errorf("zone %+v should not have stubdomains with no config-map/config-dir set", z)
+	}
+}
+
+func TestSynthesizeCorefileWithNameServers(t *testing.T) {
+	c := SynthesizeCorefile(LegacyOptions{NameServers: "10.0.0.1:53,10.0.0.2:53"})
+	z := c.Zones[0]
+	for _, p := range z.Plugins {
+		if p.Name == "forward" {
+			want := []string{".", "10.0.0.1:53", "10.0.0.2:53"}
+			if len(p.Args) != len(want) {
+				t.Fatalf("forward plugin args = %v, want %v", p.Args, want)
+			}
+			for i := range want {
+				if p.Args[i] != want[i] {
+					t.Errorf("forward plugin args = %v, want %v", p.Args, want)
+				}
+			}
+			return
+		}
+	}
+	t.Fatalf("no forward plugin found in %+v", z.Plugins)
+}
+
+func TestSynthesizeCorefileWithConfigMap(t *testing.T) {
+	c := SynthesizeCorefile(LegacyOptions{ConfigMap: "kube-dns"})
+	if !hasPlugin(c.Zones[0], "stubdomains") {
+		t.Errorf("zone %+v should have stubdomains when config-map is set", c.Zones[0])
+	}
+}
+
+func TestSynthesizeCorefileWithFederations(t *testing.T) {
+	c := SynthesizeCorefile(LegacyOptions{Federations: map[string]string{"myfed": "example.com"}})
+	if !hasPlugin(c.Zones[0], "federations") {
+		t.Errorf("zone %+v should have a federations plugin when federations are set", c.Zones[0])
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forward
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+
+	"k8s.io/dns/pkg/dns/config"
+)
+
+// Dispatcher routes a query to the Pool of the longest matching zone
+// suffix, using each pool's health-aware Select to pick an upstream.
+type Dispatcher struct {
+	mu      sync.RWMutex
+	pools   []*Pool
+	running bool
+	stopCh  <-chan struct{}
+}
+
+// NewDispatcher builds a Dispatcher from the parsed --forward-zones config.
+func NewDispatcher(zones []ZoneConfig) *Dispatcher {
+	d := &Dispatcher{}
+	for _, zc := range zones {
+		d.pools = append(d.pools, NewPool(zc))
+	}
+	return d
+}
+
+// Run starts every pool's health-check Prober, until stopCh is closed. Pools
+// added later by ApplyConfig are started the same way, since Run remembers
+// stopCh for the lifetime of the Dispatcher.
+func (d *Dispatcher) Run(stopCh <-chan struct{}) {
+	d.mu.Lock()
+	d.stopCh = stopCh
+	d.running = true
+	pools := d.pools
+	d.mu.Unlock()
+
+	for _, p := range pools {
+		go p.Run(stopCh)
+	}
+}
+
+// ApplyConfig implements config.Subscriber, replacing the catch-all "."
+// pool's upstreams with cfg.UpstreamNameservers so the nameserver pool can
+// be hot-reloaded from a ConfigMap without restarting the server. It is a
+// no-op if cfg has no upstream nameservers configured.
+func (d *Dispatcher) ApplyConfig(cfg *config.Config) error {
+	if len(cfg.UpstreamNameservers) == 0 {
+		return nil
+	}
+
+	pool := NewPool(ZoneConfig{
+		Zone:        ".",
+		Upstreams:   cfg.UpstreamNameservers,
+		Policy:      PolicyRandom,
+		HealthCheck: DefaultHealthCheck,
+		Expire:      DefaultExpire,
+	})
+
+	d.mu.Lock()
+	replaced := false
+	for i, p := range d.pools {
+		if p.zone == "." {
+			d.pools[i] = pool
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		d.pools = append(d.pools, pool)
+	}
+	running, stopCh := d.running, d.stopCh
+	d.mu.Unlock()
+
+	if running {
+		go pool.Run(stopCh)
+	}
+	return nil
+}
+
+// poolFor returns the Pool whose zone is the longest suffix match of qname,
+// or nil if no zone matches.
+func (d *Dispatcher) poolFor(qname string) *Pool {
+	qname = fqdn(strings.ToLower(qname))
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var best *Pool
+	for _, p := range d.pools {
+		if p.zone == "." || strings.HasSuffix(qname, fqdn(strings.ToLower(p.zone))) {
+			if best == nil || len(p.zone) > len(best.zone) {
+				best = p
+			}
+		}
+	}
+	return best
+}
+
+// PoolForZone returns the Pool configured for the exact zone name zone, or
+// nil if no pool was configured for it. Unlike poolFor, it does not do
+// suffix matching; it is for callers (such as a Corefile chain) that have
+// already resolved the zone themselves.
+func (d *Dispatcher) PoolForZone(zone string) *Pool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, p := range d.pools {
+		if p.zone == zone {
+			return p
+		}
+	}
+	return nil
+}
+
+// Forward selects the healthiest upstream for the zone matching req's
+// question and forwards the query to it, retrying other healthy upstreams
+// in the pool on failure. If every upstream in the matching pool is down,
+// or no zone matches, it returns a SERVFAIL response rather than blocking.
+func (d *Dispatcher) Forward(req *dns.Msg, exchange func(*dns.Msg, string) (*dns.Msg, error)) *dns.Msg {
+	fail := new(dns.Msg)
+	fail.SetRcode(req, dns.RcodeServerFailure)
+
+	if len(req.Question) == 0 {
+		return fail
+	}
+
+	return forwardVia(d.poolFor(req.Question[0].Name), req, exchange, fail)
+}
+
+// ForwardToPool forwards req via pool directly, retrying other healthy
+// upstreams in the pool on failure, bypassing zone matching entirely. It is
+// for callers that have already resolved which pool should serve req.
+func (d *Dispatcher) ForwardToPool(pool *Pool, req *dns.Msg, exchange func(*dns.Msg, string) (*dns.Msg, error)) *dns.Msg {
+	fail := new(dns.Msg)
+	fail.SetRcode(req, dns.RcodeServerFailure)
+	return forwardVia(pool, req, exchange, fail)
+}
+
+func forwardVia(pool *Pool, req *dns.Msg, exchange func(*dns.Msg, string) (*dns.Msg, error), fail *dns.Msg) *dns.Msg {
+	if pool == nil {
+		return fail
+	}
+
+	tried := map[string]bool{}
+	for {
+		upstream, err := pool.SelectExcluding(tried)
+		if err != nil {
+			return fail
+		}
+		tried[upstream.Addr] = true
+
+		resp, err := exchange(req, upstream.Addr)
+		if err != nil {
+			upstream.RecordFailure()
+			continue
+		}
+		upstream.RecordSuccess()
+		return resp
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forward
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"k8s.io/dns/pkg/dns/config"
+)
+
+func TestDispatcherForwardFallsBackOnFailure(t *testing.T) {
+	zones, err := ParseForwardZones("example.com=10.0.0.1:53,10.0.0.2:53;policy=sequential")
+	if err != nil {
+		t.Fatalf("ParseForwardZones: %v", err)
+	}
+	d := NewDispatcher(zones)
+
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.com.", dns.TypeA)
+
+	resp := d.Forward(req, func(_ *dns.Msg, addr string) (*dns.Msg, error) {
+		if addr == "10.0.0.1:53" {
+			return nil, fmt.Errorf("connection refused")
+		}
+		m := new(dns.Msg)
+		m.SetReply(req)
+		return m, nil
+	})
+
+	if resp.Rcode == dns.RcodeServerFailure {
+		t.Fatalf("Forward() returned SERVFAIL, want it to retry the healthy second upstream")
+	}
+}
+
+func TestDispatcherForwardServfailWhenAllDown(t *testing.T) {
+	zones, err := ParseForwardZones("example.com=10.0.0.1:53,10.0.0.2:53")
+	if err != nil {
+		t.Fatalf("ParseForwardZones: %v", err)
+	}
+	d := NewDispatcher(zones)
+
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.com.", dns.TypeA)
+
+	resp := d.Forward(req, func(_ *dns.Msg, _ string) (*dns.Msg, error) {
+		return nil, fmt.Errorf("connection refused")
+	})
+
+	if resp.Rcode != dns.RcodeServerFailure {
+		t.Errorf("Forward() rcode = %v, want RcodeServerFailure when every upstream fails", resp.Rcode)
+	}
+}
+
+func TestDispatcherForwardNoMatchingZone(t *testing.T) {
+	d := NewDispatcher(nil)
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.com.", dns.TypeA)
+
+	resp := d.Forward(req, func(_ *dns.Msg, _ string) (*dns.Msg, error) {
+		t.Fatal("exchange should not be called when no zone matches")
+		return nil, nil
+	})
+
+	if resp.Rcode != dns.RcodeServerFailure {
+		t.Errorf("Forward() rcode = %v, want RcodeServerFailure when no zone matches", resp.Rcode)
+	}
+}
+
+func TestDispatcherPoolForZone(t *testing.T) {
+	zones, err := ParseForwardZones("example.com=10.0.0.1:53")
+	if err != nil {
+		t.Fatalf("ParseForwardZones: %v", err)
+	}
+	d := NewDispatcher(zones)
+
+	if p := d.PoolForZone("example.com"); p == nil {
+		t.Error("PoolForZone(\"example.com\") = nil, want the configured pool")
+	}
+	if p := d.PoolForZone("other.com"); p != nil {
+		t.Error("PoolForZone(\"other.com\") = non-nil, want nil for an unconfigured zone")
+	}
+	if p := d.PoolForZone("sub.example.com"); p != nil {
+		t.Error("PoolForZone(\"sub.example.com\") = non-nil, want nil: it is not an exact match")
+	}
+}
+
+func TestDispatcherForwardToPool(t *testing.T) {
+	zones, err := ParseForwardZones("example.com=10.0.0.1:53,10.0.0.2:53;policy=sequential")
+	if err != nil {
+		t.Fatalf("ParseForwardZones: %v", err)
+	}
+	d := NewDispatcher(zones)
+	pool := d.PoolForZone("example.com")
+
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.com.", dns.TypeA)
+
+	resp := d.ForwardToPool(pool, req, func(_ *dns.Msg, addr string) (*dns.Msg, error) {
+		if addr == "10.0.0.1:53" {
+			return nil, fmt.Errorf("connection refused")
+		}
+		m := new(dns.Msg)
+		m.SetReply(req)
+		return m, nil
+	})
+	if resp.Rcode == dns.RcodeServerFailure {
+		t.Fatalf("ForwardToPool() returned SERVFAIL, want it to retry the healthy second upstream")
+	}
+}
+
+func TestDispatcherApplyConfigReplacesWildcardPool(t *testing.T) {
+	zones, err := ParseForwardZones("example.com=10.0.0.1:53,10.0.1.1:53;policy=sequential")
+	if err != nil {
+		t.Fatalf("ParseForwardZones: %v", err)
+	}
+	d := NewDispatcher(zones)
+
+	if err := d.ApplyConfig(&config.Config{UpstreamNameservers: []string{"8.8.8.8:53"}}); err != nil {
+		t.Fatalf("ApplyConfig: %v", err)
+	}
+
+	pool := d.PoolForZone(".")
+	if pool == nil {
+		t.Fatal("PoolForZone(\".\") = nil after ApplyConfig, want the new wildcard pool")
+	}
+	if got := pool.Upstreams(); len(got) != 1 || got[0].Addr != "8.8.8.8:53" {
+		t.Errorf("PoolForZone(\".\").Upstreams() = %v, want [8.8.8.8:53]", got)
+	}
+
+	if d.PoolForZone("example.com") == nil {
+		t.Error("PoolForZone(\"example.com\") = nil after ApplyConfig, want the original zone pool untouched")
+	}
+}
+
+func TestDispatcherApplyConfigNoUpstreamsIsNoop(t *testing.T) {
+	d := NewDispatcher(nil)
+	if err := d.ApplyConfig(&config.Config{}); err != nil {
+		t.Fatalf("ApplyConfig: %v", err)
+	}
+	if d.PoolForZone(".") != nil {
+		t.Error("PoolForZone(\".\") = non-nil after ApplyConfig with no upstream nameservers, want nil")
+	}
+}
+
+func TestDispatcherForwardToPoolNilPool(t *testing.T) {
+	d := NewDispatcher(nil)
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.com.", dns.TypeA)
+
+	resp := d.ForwardToPool(nil, req, func(_ *dns.Msg, _ string) (*dns.Msg, error) {
+		t.Fatal("exchange should not be called with a nil pool")
+		return nil, nil
+	})
+	if resp.Rcode != dns.RcodeServerFailure {
+		t.Errorf("ForwardToPool(nil, ...) rcode = %v, want RcodeServerFailure", resp.Rcode)
+	}
+}
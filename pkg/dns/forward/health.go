@@ -0,0 +1,168 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forward
+
+import (
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"k8s.io/dns/pkg/dns/metrics"
+)
+
+// State is the health of a single upstream nameserver.
+type State int
+
+const (
+	// StateActive means the upstream is healthy and eligible for use.
+	StateActive State = iota
+	// StateFailing means recent queries have failed but the upstream is
+	// still being tried; it moves to StateDown after the probe also fails.
+	StateFailing
+	// StateDown means the upstream is excluded from selection until a
+	// probe succeeds again.
+	StateDown
+)
+
+// Upstream tracks the health state of a single "host:port" nameserver.
+type Upstream struct {
+	Addr string
+
+	mu             sync.Mutex
+	state          State
+	consecutiveErr int
+	downSince      time.Time
+}
+
+// failThreshold is the number of consecutive passive failures before an
+// upstream is marked failing (and thus eligible to be probed down).
+const failThreshold = 3
+
+func newUpstream(addr string) *Upstream {
+	return &Upstream{Addr: addr, state: StateActive}
+}
+
+// State returns the upstream's current health state.
+func (u *Upstream) State() State {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.state
+}
+
+// RecordSuccess marks a successful query against this upstream, restoring
+// it to StateActive.
+func (u *Upstream) RecordSuccess() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.consecutiveErr = 0
+	u.state = StateActive
+	u.downSince = time.Time{}
+}
+
+// RecordFailure marks a failed query against this upstream, demoting it to
+// StateFailing after failThreshold consecutive failures.
+func (u *Upstream) RecordFailure() {
+	metrics.RecordUpstreamFailure(u.Addr)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.consecutiveErr++
+	if u.consecutiveErr >= failThreshold && u.state == StateActive {
+		u.state = StateFailing
+	}
+}
+
+// setState is used by the prober to move an upstream to StateDown or back
+// to StateActive based on active probe results.
+func (u *Upstream) setState(s State) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if s == StateDown && u.state != StateDown {
+		u.downSince = time.Now()
+	}
+	u.state = s
+	if s == StateActive {
+		u.consecutiveErr = 0
+		u.downSince = time.Time{}
+	}
+}
+
+// DownFor returns how long the upstream has been continuously StateDown, or
+// 0 if it is not currently down.
+func (u *Upstream) DownFor() time.Duration {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.state != StateDown || u.downSince.IsZero() {
+		return 0
+	}
+	return time.Since(u.downSince)
+}
+
+// Prober periodically sends a lightweight probe query to every upstream in
+// a Pool that is not StateActive and updates its health state based on the
+// result. StateActive upstreams are not probed.
+type Prober struct {
+	interval time.Duration
+	exchange func(addr string) error
+}
+
+// NewProber returns a Prober that probes every interval using exchange to
+// perform the actual query. exchange is injected so tests and callers can
+// avoid real network I/O.
+func NewProber(interval time.Duration, exchange func(addr string) error) *Prober {
+	if exchange == nil {
+		exchange = defaultExchange
+	}
+	return &Prober{interval: interval, exchange: exchange}
+}
+
+func defaultExchange(addr string) error {
+	m := new(dns.Msg)
+	m.SetQuestion(".", dns.TypeNS)
+	c := new(dns.Client)
+	c.Timeout = 2 * time.Second
+	_, _, err := c.Exchange(m, addr)
+	return err
+}
+
+// Run probes every upstream in pool on a ticker until stopCh is closed.
+func (p *Prober) Run(pool *Pool, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			p.probeOnce(pool)
+		}
+	}
+}
+
+func (p *Prober) probeOnce(pool *Pool) {
+	for _, u := range pool.Upstreams() {
+		if u.State() == StateActive {
+			continue
+		}
+		if err := p.exchange(u.Addr); err != nil {
+			u.setState(StateDown)
+		} else {
+			u.setState(StateActive)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forward
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpstreamRecordFailureThreshold(t *testing.T) {
+	u := newUpstream("10.0.0.1:53")
+	for i := 0; i < failThreshold-1; i++ {
+		u.RecordFailure()
+		if u.State() != StateActive {
+			t.Fatalf("after %d failures, state = %v, want StateActive", i+1, u.State())
+		}
+	}
+	u.RecordFailure()
+	if u.State() != StateFailing {
+		t.Fatalf("after %d failures, state = %v, want StateFailing", failThreshold, u.State())
+	}
+}
+
+func TestUpstreamRecordSuccessResets(t *testing.T) {
+	u := newUpstream("10.0.0.1:53")
+	u.setState(StateDown)
+	u.RecordSuccess()
+	if u.State() != StateActive {
+		t.Fatalf("State() after RecordSuccess = %v, want StateActive", u.State())
+	}
+	if u.DownFor() != 0 {
+		t.Fatalf("DownFor() after RecordSuccess = %v, want 0", u.DownFor())
+	}
+}
+
+func TestUpstreamDownFor(t *testing.T) {
+	u := newUpstream("10.0.0.1:53")
+	if got := u.DownFor(); got != 0 {
+		t.Fatalf("DownFor() on a healthy upstream = %v, want 0", got)
+	}
+
+	u.setState(StateDown)
+	time.Sleep(5 * time.Millisecond)
+	if got := u.DownFor(); got < 5*time.Millisecond {
+		t.Errorf("DownFor() = %v, want at least 5ms", got)
+	}
+}
+
+func TestProberRestoresUpstream(t *testing.T) {
+	pool := NewPool(ZoneConfig{
+		Zone:        ".",
+		Upstreams:   []string{"10.0.0.1:53"},
+		Policy:      PolicyRandom,
+		HealthCheck: time.Millisecond,
+		Expire:      time.Minute,
+	})
+	pool.upstreams[0].setState(StateFailing)
+
+	prober := NewProber(time.Millisecond, func(addr string) error { return nil })
+	prober.probeOnce(pool)
+
+	if got := pool.upstreams[0].State(); got != StateActive {
+		t.Errorf("state after successful probe = %v, want StateActive", got)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forward
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// Pool is the set of upstreams configured for a single zone, selected
+// according to Policy. HealthCheck and Expire govern, respectively, how
+// often the pool's Prober actively probes down upstreams and how long an
+// upstream stays excluded from selection before it is given another chance
+// even without a successful probe.
+type Pool struct {
+	zone        string
+	policy      Policy
+	healthCheck time.Duration
+	expire      time.Duration
+	upstreams   []*Upstream
+	next        uint32 // round-robin cursor
+	prober      *Prober
+}
+
+// NewPool builds a Pool for the given zone config, wiring up a Prober that
+// probes its upstreams every zc.HealthCheck.
+func NewPool(zc ZoneConfig) *Pool {
+	p := &Pool{
+		zone:        zc.Zone,
+		policy:      zc.Policy,
+		healthCheck: zc.HealthCheck,
+		expire:      zc.Expire,
+	}
+	for _, addr := range zc.Upstreams {
+		p.upstreams = append(p.upstreams, newUpstream(addr))
+	}
+	p.prober = NewProber(p.healthCheck, nil)
+	return p
+}
+
+// Upstreams returns every upstream in the pool, regardless of health.
+func (p *Pool) Upstreams() []*Upstream {
+	return p.upstreams
+}
+
+// Run starts the pool's Prober, probing down upstreams every HealthCheck
+// interval until stopCh is closed.
+func (p *Pool) Run(stopCh <-chan struct{}) {
+	p.prober.Run(p, stopCh)
+}
+
+// ErrAllUpstreamsDown is returned by Select when every upstream in the pool
+// is StateDown; callers should answer the query SERVFAIL rather than block.
+var ErrAllUpstreamsDown = fmt.Errorf("all upstreams down")
+
+// Select returns the next upstream to use according to the pool's policy,
+// or ErrAllUpstreamsDown if none are healthy. It is equivalent to
+// SelectExcluding(nil).
+func (p *Pool) Select() (*Upstream, error) {
+	return p.SelectExcluding(nil)
+}
+
+// SelectExcluding returns the next upstream to use according to the pool's
+// policy, skipping any address in excluded (e.g. upstreams already tried for
+// this query), or ErrAllUpstreamsDown if none remain.
+func (p *Pool) SelectExcluding(excluded map[string]bool) (*Upstream, error) {
+	healthy := p.eligible(excluded)
+	if len(healthy) == 0 {
+		return nil, ErrAllUpstreamsDown
+	}
+
+	switch p.policy {
+	case PolicyRoundRobin:
+		idx := atomic.AddUint32(&p.next, 1)
+		return healthy[int(idx)%len(healthy)], nil
+	case PolicySequential:
+		return healthy[0], nil
+	default: // PolicyRandom
+		return healthy[rand.Intn(len(healthy))], nil
+	}
+}
+
+// eligible returns the upstreams that are not excluded and are either not
+// StateDown or have been down longer than p.expire, so a downed upstream
+// always gets another chance rather than staying excluded forever if the
+// Prober can't reach it either.
+func (p *Pool) eligible(excluded map[string]bool) []*Upstream {
+	var out []*Upstream
+	for _, u := range p.upstreams {
+		if excluded[u.Addr] {
+			continue
+		}
+		if u.State() != StateDown || u.DownFor() >= p.expire {
+			out = append(out, u)
+		}
+	}
+	return out
+}
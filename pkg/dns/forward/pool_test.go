@@ -0,0 +1,80 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forward
+
+import (
+	"testing"
+	"time"
+)
+
+func testPool(policy Policy) *Pool {
+	return NewPool(ZoneConfig{
+		Zone:        "example.com",
+		Upstreams:   []string{"10.0.0.1:53", "10.0.0.2:53"},
+		Policy:      policy,
+		HealthCheck: time.Second,
+		Expire:      time.Minute,
+	})
+}
+
+func TestPoolSelectExcludingFallsBackToOtherUpstream(t *testing.T) {
+	for _, policy := range []Policy{PolicyRandom, PolicyRoundRobin, PolicySequential} {
+		p := testPool(policy)
+		excluded := map[string]bool{"10.0.0.1:53": true}
+
+		u, err := p.SelectExcluding(excluded)
+		if err != nil {
+			t.Fatalf("policy %s: SelectExcluding returned error: %v", policy, err)
+		}
+		if u.Addr != "10.0.0.2:53" {
+			t.Errorf("policy %s: SelectExcluding = %s, want 10.0.0.2:53", policy, u.Addr)
+		}
+	}
+}
+
+func TestPoolSelectExcludingAllReturnsErr(t *testing.T) {
+	p := testPool(PolicyRandom)
+	excluded := map[string]bool{"10.0.0.1:53": true, "10.0.0.2:53": true}
+	if _, err := p.SelectExcluding(excluded); err != ErrAllUpstreamsDown {
+		t.Errorf("SelectExcluding with every upstream tried = %v, want ErrAllUpstreamsDown", err)
+	}
+}
+
+func TestPoolDownUpstreamExcludedUntilExpired(t *testing.T) {
+	p := NewPool(ZoneConfig{
+		Zone:        "example.com",
+		Upstreams:   []string{"10.0.0.1:53", "10.0.0.2:53"},
+		Policy:      PolicySequential,
+		HealthCheck: time.Second,
+		Expire:      10 * time.Millisecond,
+	})
+	p.upstreams[0].setState(StateDown)
+
+	if u, err := p.Select(); err != nil || u.Addr != "10.0.0.2:53" {
+		t.Fatalf("Select() = %v, %v, want 10.0.0.2:53 while upstream 0 is freshly down", u, err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	u, err := p.Select()
+	if err != nil {
+		t.Fatalf("Select() returned error after expire: %v", err)
+	}
+	if u.Addr != "10.0.0.1:53" {
+		t.Errorf("Select() = %s after expire, want the expired upstream to be eligible again", u.Addr)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package forward implements per-zone conditional forwarding to upstream
+// nameservers, tracking each upstream's health so that failing servers are
+// taken out of rotation instead of being retried on every query.
+package forward
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Policy selects which healthy upstream serves the next query.
+type Policy string
+
+const (
+	// PolicyRandom picks a uniformly random healthy upstream.
+	PolicyRandom Policy = "random"
+	// PolicyRoundRobin cycles through healthy upstreams in order.
+	PolicyRoundRobin Policy = "round_robin"
+	// PolicySequential always prefers the first healthy upstream, falling
+	// back to the next only when it is down.
+	PolicySequential Policy = "sequential"
+)
+
+const (
+	// DefaultHealthCheck is the probe interval used when a ZoneConfig does
+	// not set HealthCheck.
+	DefaultHealthCheck = 5 * time.Second
+	// DefaultExpire is the downtime used when a ZoneConfig does not set
+	// Expire.
+	DefaultExpire = 30 * time.Second
+)
+
+// ZoneConfig is a single parsed --forward-zones entry.
+type ZoneConfig struct {
+	Zone        string
+	Upstreams   []string
+	Policy      Policy
+	HealthCheck time.Duration
+	Expire      time.Duration
+}
+
+// ParseForwardZones parses a --forward-zones flag value, which is a
+// semicolon-separated list of entries of the form:
+//
+//	<zone>=<host:port>[,<host:port>...][;policy=<policy>][;health_check=<dur>][;expire=<dur>]
+//
+// e.g. "example.com=10.0.0.1:53,10.0.0.2:53;policy=round_robin;health_check=5s;expire=30s".
+func ParseForwardZones(s string) ([]ZoneConfig, error) {
+	var zones []ZoneConfig
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.HasPrefix(entry, "policy=") || strings.HasPrefix(entry, "health_check=") || strings.HasPrefix(entry, "expire=") {
+			if len(zones) == 0 {
+				return nil, fmt.Errorf("forward-zones: modifier %q with no preceding zone", entry)
+			}
+			if err := applyModifier(&zones[len(zones)-1], entry); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		zc, err := parseZone(entry)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, zc)
+	}
+	return zones, nil
+}
+
+func parseZone(entry string) (ZoneConfig, error) {
+	parts := strings.SplitN(entry, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ZoneConfig{}, fmt.Errorf("forward-zones: invalid entry %q, want <zone>=<upstreams>", entry)
+	}
+	zc := ZoneConfig{
+		Zone:        parts[0],
+		Upstreams:   strings.Split(parts[1], ","),
+		Policy:      PolicyRandom,
+		HealthCheck: DefaultHealthCheck,
+		Expire:      DefaultExpire,
+	}
+	return zc, nil
+}
+
+func applyModifier(zc *ZoneConfig, modifier string) error {
+	parts := strings.SplitN(modifier, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("forward-zones: invalid modifier %q", modifier)
+	}
+	key, val := parts[0], parts[1]
+	switch key {
+	case "policy":
+		switch Policy(val) {
+		case PolicyRandom, PolicyRoundRobin, PolicySequential:
+			zc.Policy = Policy(val)
+		default:
+			return fmt.Errorf("forward-zones: unknown policy %q", val)
+		}
+	case "health_check":
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("forward-zones: invalid health_check %q: %v", val, err)
+		}
+		zc.HealthCheck = d
+	case "expire":
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("forward-zones: invalid expire %q: %v", val, err)
+		}
+		zc.Expire = d
+	default:
+		return fmt.Errorf("forward-zones: unknown modifier %q", key)
+	}
+	return nil
+}
+
+// ZonesFromNameServers translates the legacy --nameservers flag into the
+// equivalent single wildcard ZoneConfig, so it keeps working as a shim once
+// --forward-zones is the primary configuration surface.
+func ZonesFromNameServers(nameServers string) []ZoneConfig {
+	if nameServers == "" {
+		return nil
+	}
+	return []ZoneConfig{{
+		Zone:        ".",
+		Upstreams:   strings.Split(nameServers, ","),
+		Policy:      PolicyRandom,
+		HealthCheck: DefaultHealthCheck,
+		Expire:      DefaultExpire,
+	}}
+}
+
+// fqdn appends a trailing dot to s if it does not already have one, so zone
+// names and query names compare as fully qualified domain names regardless
+// of how each was written.
+func fqdn(s string) string {
+	if strings.HasSuffix(s, ".") {
+		return s
+	}
+	return s + "."
+}
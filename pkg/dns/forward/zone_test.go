@@ -0,0 +1,126 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package forward
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseForwardZones(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    []ZoneConfig
+		wantErr bool
+	}{
+		{
+			name: "single zone with defaults",
+			in:   "example.com=10.0.0.1:53",
+			want: []ZoneConfig{{
+				Zone:        "example.com",
+				Upstreams:   []string{"10.0.0.1:53"},
+				Policy:      PolicyRandom,
+				HealthCheck: DefaultHealthCheck,
+				Expire:      DefaultExpire,
+			}},
+		},
+		{
+			name: "zone with modifiers and multiple upstreams",
+			in:   "example.com=10.0.0.1:53,10.0.0.2:53;policy=round_robin;health_check=5s;expire=30s",
+			want: []ZoneConfig{{
+				Zone:        "example.com",
+				Upstreams:   []string{"10.0.0.1:53", "10.0.0.2:53"},
+				Policy:      PolicyRoundRobin,
+				HealthCheck: 5 * time.Second,
+				Expire:      30 * time.Second,
+			}},
+		},
+		{
+			name: "multiple zones",
+			in:   "example.com=10.0.0.1:53;policy=sequential;example.org=10.0.0.2:53",
+			want: []ZoneConfig{
+				{
+					Zone:        "example.com",
+					Upstreams:   []string{"10.0.0.1:53"},
+					Policy:      PolicySequential,
+					HealthCheck: DefaultHealthCheck,
+					Expire:      DefaultExpire,
+				},
+				{
+					Zone:        "example.org",
+					Upstreams:   []string{"10.0.0.2:53"},
+					Policy:      PolicyRandom,
+					HealthCheck: DefaultHealthCheck,
+					Expire:      DefaultExpire,
+				},
+			},
+		},
+		{
+			name:    "modifier with no preceding zone",
+			in:      "policy=round_robin",
+			wantErr: true,
+		},
+		{
+			name:    "unknown policy",
+			in:      "example.com=10.0.0.1:53;policy=bogus",
+			wantErr: true,
+		},
+		{
+			name:    "malformed entry",
+			in:      "example.com",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ParseForwardZones(c.in)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("ParseForwardZones(%q) = %v, want error", c.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseForwardZones(%q) returned error: %v", c.in, err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("ParseForwardZones(%q) = %+v, want %+v", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestZonesFromNameServers(t *testing.T) {
+	if got := ZonesFromNameServers(""); got != nil {
+		t.Errorf("ZonesFromNameServers(\"\") = %+v, want nil", got)
+	}
+
+	got := ZonesFromNameServers("8.8.8.8:53,8.8.4.4:53")
+	want := []ZoneConfig{{
+		Zone:        ".",
+		Upstreams:   []string{"8.8.8.8:53", "8.8.4.4:53"},
+		Policy:      PolicyRandom,
+		HealthCheck: DefaultHealthCheck,
+		Expire:      DefaultExpire,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ZonesFromNameServers(...) = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics exports Prometheus metrics for query latency, upstream
+// health and config reloads, registered with the default Prometheus
+// registry.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kubedns"
+
+var (
+	// QueryDuration observes query latency in seconds, broken down by qtype,
+	// rcode and zone.
+	QueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "query_duration_seconds",
+		Help:      "Histogram of the time (in seconds) each DNS query took to resolve.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"qtype", "rcode", "zone"})
+
+	// UpstreamFailures counts failed queries to an upstream nameserver, by
+	// upstream address.
+	UpstreamFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_failures_total",
+		Help:      "Number of failed queries to an upstream nameserver.",
+	}, []string{"upstream"})
+
+	// ConfigReloads counts config reload attempts, by result ("success" or
+	// "failure").
+	ConfigReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Number of configuration reloads, by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(QueryDuration, UpstreamFailures, ConfigReloads)
+}
+
+// RecordQuery observes a completed query's latency and outcome.
+func RecordQuery(qtype, rcode, zone string, duration time.Duration) {
+	QueryDuration.WithLabelValues(qtype, rcode, zone).Observe(duration.Seconds())
+}
+
+// RecordUpstreamFailure counts a failed query to the upstream nameserver at
+// addr.
+func RecordUpstreamFailure(addr string) {
+	UpstreamFailures.WithLabelValues(addr).Inc()
+}
+
+// RecordConfigReload counts a config reload attempt with the given result
+// ("success" or "failure").
+func RecordConfigReload(result string) {
+	ConfigReloads.WithLabelValues(result).Inc()
+}
+
+// Handler returns the HTTP handler that serves metrics in the Prometheus
+// exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
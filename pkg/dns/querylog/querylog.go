@@ -0,0 +1,136 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package querylog emits structured per-query log records (client IP,
+// qname, qtype, rcode, latency, upstream used) at a configurable sampling
+// rate, for debugging high-cardinality resolution issues.
+package querylog
+
+import (
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Mode controls how much per-query detail is logged.
+type Mode string
+
+const (
+	// Off disables query logging entirely.
+	Off Mode = "off"
+	// Sampled logs a random sample of queries.
+	Sampled Mode = "sampled"
+	// Full logs every query.
+	Full Mode = "full"
+)
+
+// Format controls how query log entries are rendered. Other server logs are
+// not affected by it.
+type Format string
+
+const (
+	// FormatText renders logs as glog's usual plain text.
+	FormatText Format = "text"
+	// FormatJSON renders each query log entry as a single JSON object.
+	FormatJSON Format = "json"
+)
+
+// Entry is a single structured query log record.
+type Entry struct {
+	ClientIP string
+	QName    string
+	QType    string
+	RCode    string
+	Latency  time.Duration
+	Upstream string
+}
+
+// MarshalJSON renders Latency in milliseconds rather than encoding/json's
+// default of raw time.Duration nanoseconds, so the latencyMs field matches
+// its name.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type jsonEntry struct {
+		ClientIP  string `json:"clientIP"`
+		QName     string `json:"qname"`
+		QType     string `json:"qtype"`
+		RCode     string `json:"rcode"`
+		LatencyMs int64  `json:"latencyMs"`
+		Upstream  string `json:"upstream,omitempty"`
+	}
+	return json.Marshal(jsonEntry{
+		ClientIP:  e.ClientIP,
+		QName:     e.QName,
+		QType:     e.QType,
+		RCode:     e.RCode,
+		LatencyMs: int64(e.Latency / time.Millisecond),
+		Upstream:  e.Upstream,
+	})
+}
+
+// Logger emits Entries according to its Mode.
+type Logger struct {
+	mode       Mode
+	format     Format
+	sampleRate float64
+}
+
+// defaultSampleRate is used in Sampled mode: roughly 1 in 100 queries.
+const defaultSampleRate = 0.01
+
+// NewLogger returns a Logger operating in the given mode and format. An
+// unrecognized mode is treated as Off and an unrecognized format as
+// FormatText.
+func NewLogger(mode Mode, format Format) *Logger {
+	l := &Logger{format: FormatText}
+	if format == FormatJSON {
+		l.format = FormatJSON
+	}
+	switch mode {
+	case Sampled, Full:
+		l.mode = mode
+		l.sampleRate = defaultSampleRate
+	default:
+		l.mode = Off
+	}
+	return l
+}
+
+// Log emits e if the Logger's mode and sampling decide it should be logged.
+func (l *Logger) Log(e Entry) {
+	switch l.mode {
+	case Off:
+		return
+	case Sampled:
+		if rand.Float64() > l.sampleRate {
+			return
+		}
+	}
+
+	if l.format == FormatJSON {
+		b, err := json.Marshal(e)
+		if err != nil {
+			glog.Errorf("query-log: failed to marshal entry: %v", err)
+			return
+		}
+		glog.V(0).Infof("%s", b)
+		return
+	}
+
+	glog.V(0).Infof("query-log: client=%s qname=%s qtype=%s rcode=%s latency=%s upstream=%s",
+		e.ClientIP, e.QName, e.QType, e.RCode, e.Latency, e.Upstream)
+}
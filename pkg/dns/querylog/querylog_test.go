@@ -0,0 +1,49 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package querylog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEntryMarshalJSONLatencyInMilliseconds(t *testing.T) {
+	e := Entry{
+		ClientIP: "10.1.2.3",
+		QName:    "www.example.com.",
+		QType:    "A",
+		RCode:    "NOERROR",
+		Latency:  250 * time.Millisecond,
+		Upstream: "8.8.8.8:53",
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	var decoded struct {
+		LatencyMs int64 `json:"latencyMs"`
+	}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if decoded.LatencyMs != 250 {
+		t.Errorf("latencyMs = %d, want 250", decoded.LatencyMs)
+	}
+}
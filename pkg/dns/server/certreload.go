@@ -0,0 +1,140 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package server adds DNS-over-TLS (RFC 7858) and DNS-over-HTTPS (RFC 8484)
+// listeners alongside the existing UDP/TCP port 53 listener.
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// mtimePollInterval is how often CertReloader checks the certificate and
+// key files for changes when SIGHUP is not used to trigger a reload.
+const mtimePollInterval = 30 * time.Second
+
+// CertReloader serves a hot-reloadable TLS certificate, picking up a new
+// cert/key pair on SIGHUP or when the files' mtimes change, so
+// cert-manager-issued secrets rotate without restarting the server.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	cert atomic.Value // holds *tls.Certificate
+
+	mu       sync.Mutex
+	lastCert time.Time
+	lastKey  time.Time
+}
+
+// NewCertReloader loads certFile/keyFile once and returns a CertReloader
+// serving them, ready to have Watch called on it.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is a tls.Config.GetCertificate callback returning the
+// currently loaded certificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := r.cert.Load().(*tls.Certificate)
+	if cert == nil {
+		return nil, fmt.Errorf("no certificate loaded")
+	}
+	return cert, nil
+}
+
+// Watch reloads the certificate whenever the process receives SIGHUP, and
+// independently polls the cert/key files' mtimes every mtimePollInterval,
+// until stopCh is closed.
+func (r *CertReloader) Watch(stopCh <-chan struct{}) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	ticker := time.NewTicker(mtimePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-sigCh:
+			glog.Infof("received SIGHUP, reloading TLS certificate from %s", r.certFile)
+			if err := r.reload(); err != nil {
+				glog.Errorf("failed to reload TLS certificate: %v", err)
+			}
+		case <-ticker.C:
+			if r.changed() {
+				glog.Infof("detected change to %s, reloading TLS certificate", r.certFile)
+				if err := r.reload(); err != nil {
+					glog.Errorf("failed to reload TLS certificate: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func (r *CertReloader) changed() bool {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return certInfo.ModTime().After(r.lastCert) || keyInfo.ModTime().After(r.lastKey)
+}
+
+func (r *CertReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS keypair (%s, %s): %v", r.certFile, r.keyFile, err)
+	}
+
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert.Store(&cert)
+
+	r.mu.Lock()
+	r.lastCert = certInfo.ModTime()
+	r.lastKey = keyInfo.ModTime()
+	r.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/miekg/dns"
+)
+
+// dohContentType is the required Content-Type for DoH requests and
+// responses, per RFC 8484.
+const dohContentType = "application/dns-message"
+
+// DoHHandler implements a DNS-over-HTTPS (RFC 8484) endpoint, accepting
+// both the GET (?dns=<base64url>) and POST (application/dns-message) forms.
+type DoHHandler struct {
+	// Exchange resolves a parsed DNS query received from r and returns the
+	// response. r is passed through (rather than just its parsed message)
+	// so implementations can attribute the query to its real client, e.g.
+	// r.RemoteAddr, for query logging.
+	Exchange func(r *http.Request, msg *dns.Msg) (*dns.Msg, error)
+}
+
+func (h *DoHHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := h.parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.Exchange(r, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to resolve query: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	out, err := resp.Pack()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to pack response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", dohContentType)
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", minTTL(resp)))
+	w.Write(out)
+}
+
+func (h *DoHHandler) parseRequest(r *http.Request) (*dns.Msg, error) {
+	var raw []byte
+	switch r.Method {
+	case http.MethodGet:
+		encoded := r.URL.Query().Get("dns")
+		if encoded == "" {
+			return nil, fmt.Errorf("missing dns query parameter")
+		}
+		decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64url dns parameter: %v", err)
+		}
+		raw = decoded
+	case http.MethodPost:
+		if ct := r.Header.Get("Content-Type"); ct != dohContentType {
+			return nil, fmt.Errorf("unsupported Content-Type %q, want %q", ct, dohContentType)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %v", err)
+		}
+		raw = body
+	default:
+		return nil, fmt.Errorf("unsupported method %q", r.Method)
+	}
+
+	msg := new(dns.Msg)
+	if err := msg.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("invalid DNS message: %v", err)
+	}
+	return msg, nil
+}
+
+// minTTL returns the smallest TTL among resp's answer records, so the
+// Cache-Control header never outlives the shortest-lived record, or 0 if
+// there are no answers.
+func minTTL(resp *dns.Msg) uint32 {
+	var min uint32
+	for i, rr := range resp.Answer {
+		ttl := rr.Header().Ttl
+		if i == 0 || ttl < min {
+			min = ttl
+		}
+	}
+	return min
+}
+
+// NewDoHServer returns an *http.Server serving a DoH handler at addr/path,
+// using reloader for a hot-reloadable TLS certificate.
+func NewDoHServer(addr, path string, handler *DoHHandler, reloader *CertReloader) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	return &http.Server{
+		Addr:    addr,
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			GetCertificate: reloader.GetCertificate,
+		},
+	}
+}
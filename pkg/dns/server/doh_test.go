@@ -0,0 +1,140 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func packQuery(t *testing.T, qname string) []byte {
+	t.Helper()
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(qname), dns.TypeA)
+	raw, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("Pack() = %v", err)
+	}
+	return raw
+}
+
+func TestDoHHandlerParseRequestGet(t *testing.T) {
+	raw := packQuery(t, "example.com")
+	encoded := base64.RawURLEncoding.EncodeToString(raw)
+
+	h := &DoHHandler{}
+	req := httptest.NewRequest(http.MethodGet, "/dns-query?dns="+encoded, nil)
+	msg, err := h.parseRequest(req)
+	if err != nil {
+		t.Fatalf("parseRequest() = %v, want no error", err)
+	}
+	if len(msg.Question) != 1 || msg.Question[0].Name != "example.com." {
+		t.Errorf("parseRequest() question = %+v, want example.com.", msg.Question)
+	}
+}
+
+func TestDoHHandlerParseRequestGetMissingParam(t *testing.T) {
+	h := &DoHHandler{}
+	req := httptest.NewRequest(http.MethodGet, "/dns-query", nil)
+	if _, err := h.parseRequest(req); err == nil {
+		t.Fatal("parseRequest() = nil error, want an error for missing dns parameter")
+	}
+}
+
+func TestDoHHandlerParseRequestGetInvalidBase64(t *testing.T) {
+	h := &DoHHandler{}
+	req := httptest.NewRequest(http.MethodGet, "/dns-query?dns=not-valid-base64!!", nil)
+	if _, err := h.parseRequest(req); err == nil {
+		t.Fatal("parseRequest() = nil error, want an error for invalid base64url")
+	}
+}
+
+func TestDoHHandlerParseRequestPost(t *testing.T) {
+	raw := packQuery(t, "example.com")
+
+	h := &DoHHandler{}
+	req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(raw))
+	req.Header.Set("Content-Type", dohContentType)
+	msg, err := h.parseRequest(req)
+	if err != nil {
+		t.Fatalf("parseRequest() = %v, want no error", err)
+	}
+	if len(msg.Question) != 1 || msg.Question[0].Name != "example.com." {
+		t.Errorf("parseRequest() question = %+v, want example.com.", msg.Question)
+	}
+}
+
+func TestDoHHandlerParseRequestPostWrongContentType(t *testing.T) {
+	raw := packQuery(t, "example.com")
+
+	h := &DoHHandler{}
+	req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if _, err := h.parseRequest(req); err == nil {
+		t.Fatal("parseRequest() = nil error, want an error for wrong Content-Type")
+	}
+}
+
+func TestDoHHandlerParseRequestPostInvalidMessage(t *testing.T) {
+	h := &DoHHandler{}
+	req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader([]byte("not a dns message")))
+	req.Header.Set("Content-Type", dohContentType)
+	if _, err := h.parseRequest(req); err == nil {
+		t.Fatal("parseRequest() = nil error, want an error for an unparseable message")
+	}
+}
+
+func TestDoHHandlerParseRequestUnsupportedMethod(t *testing.T) {
+	h := &DoHHandler{}
+	req := httptest.NewRequest(http.MethodPut, "/dns-query", nil)
+	if _, err := h.parseRequest(req); err == nil {
+		t.Fatal("parseRequest() = nil error, want an error for an unsupported method")
+	}
+}
+
+func newA(name string, ttl uint32) *dns.A {
+	return &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Ttl: ttl}}
+}
+
+func TestMinTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		rrs  []dns.RR
+		want uint32
+	}{
+		{name: "no answers", rrs: nil, want: 0},
+		{name: "single answer", rrs: []dns.RR{newA("a.", 60)}, want: 60},
+		{
+			name: "picks the smallest",
+			rrs:  []dns.RR{newA("a.", 300), newA("b.", 30), newA("c.", 120)},
+			want: 30,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &dns.Msg{Answer: tt.rrs}
+			if got := minTTL(resp); got != tt.want {
+				t.Errorf("minTTL() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
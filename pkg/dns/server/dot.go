@@ -0,0 +1,62 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// TLSServer is a DNS-over-TLS (RFC 7858) listener. It reuses the standard
+// two-byte length-prefixed TCP message framing, as required by RFC 7858,
+// simply carried over a TLS-wrapped connection.
+type TLSServer struct {
+	srv *dns.Server
+}
+
+// NewTLSServer returns a TLSServer listening on addr (host:port), serving
+// handler, using reloader for its certificate so rotated cert-manager
+// secrets are picked up without a restart.
+func NewTLSServer(addr string, handler dns.Handler, reloader *CertReloader) *TLSServer {
+	tlsConfig := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+	}
+	return &TLSServer{
+		srv: &dns.Server{
+			Addr:      addr,
+			Net:       "tcp-tls",
+			TLSConfig: tlsConfig,
+			Handler:   handler,
+		},
+	}
+}
+
+// ListenAndServe starts the DoT listener and blocks until it is stopped or
+// fails.
+func (s *TLSServer) ListenAndServe() error {
+	if err := s.srv.ListenAndServe(); err != nil {
+		return fmt.Errorf("DNS-over-TLS server failed: %v", err)
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the listener.
+func (s *TLSServer) Shutdown() error {
+	return s.srv.Shutdown()
+}